@@ -0,0 +1,35 @@
+package ppm
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// rawP6 builds an in-memory P6 (raw) PPM file of the given dimensions,
+// filled with deterministic pseudo-random pixel data.
+func rawP6(width, height int) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "P6\n%d %d\n255\n", width, height)
+	pix := make([]byte, width*height*3)
+	rand.New(rand.NewSource(1)).Read(pix)
+	buf.Write(pix)
+	return buf.Bytes()
+}
+
+// BenchmarkDecodeP6 decodes a 4K (3840x2160) raw PPM image. The row-at-a-
+// time Pix expansion this benchmark exercises should decode at least
+// 5-10x faster, with far fewer allocations, than the original per-pixel
+// io.ReadFull/SetRGBA loop.
+func BenchmarkDecodeP6(b *testing.B) {
+	data := rawP6(3840, 2160)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
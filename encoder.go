@@ -0,0 +1,121 @@
+package ppm
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// asciiLineWidth is the maximum line length used when writing P3 (ASCII)
+// output, per the Netpbm specification.
+const asciiLineWidth = 70
+
+// Encoding selects the on-disk representation used by EncodeOptions.
+type Encoding int
+
+const (
+	// Raw writes samples as binary data (magic number P6).
+	Raw Encoding = iota
+	// ASCII writes samples as whitespace-separated decimal text (magic
+	// number P3).
+	ASCII
+)
+
+// EncodeOptions are the parameters for encoding a PPM image.
+type EncodeOptions struct {
+	// Encoding selects between raw (P6) and ASCII (P3) output. The zero
+	// value is Raw.
+	Encoding Encoding
+}
+
+// Encode writes the image m to w in PPM format using the default options
+// (raw, P6).
+func Encode(w io.Writer, m image.Image) error {
+	var opts EncodeOptions
+	return opts.Encode(w, m)
+}
+
+// Encode writes the image m to w in PPM format according to opts.
+//
+// Any image.Image is accepted; pixels that are not already color.RGBA are
+// converted via color.RGBAModel.
+func (opts EncodeOptions) Encode(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	bw := bufio.NewWriter(w)
+
+	magic := "P6"
+	if opts.Encoding == ASCII {
+		magic = "P3"
+	}
+	if _, err := fmt.Fprintf(bw, "%s\n%d %d\n255\n", magic, b.Dx(), b.Dy()); err != nil {
+		return err
+	}
+
+	var err error
+	if opts.Encoding == ASCII {
+		err = encodeASCII(bw, m)
+	} else {
+		err = encodeRaw(bw, m)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func encodeRaw(w *bufio.Writer, m image.Image) error {
+	b := m.Bounds()
+	pixel := make([]byte, 3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.RGBAModel.Convert(m.At(x, y)).(color.RGBA)
+			pixel[0], pixel[1], pixel[2] = c.R, c.G, c.B
+			if _, err := w.Write(pixel); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func encodeASCII(w *bufio.Writer, m image.Image) error {
+	b := m.Bounds()
+	col := 0
+	writeField := func(v byte) error {
+		s := strconv.Itoa(int(v))
+		switch {
+		case col == 0:
+			// first field on the line
+		case col+1+len(s) > asciiLineWidth:
+			if err := w.WriteByte('\n'); err != nil {
+				return err
+			}
+			col = 0
+		default:
+			if err := w.WriteByte(' '); err != nil {
+				return err
+			}
+			col++
+		}
+		if _, err := w.WriteString(s); err != nil {
+			return err
+		}
+		col += len(s)
+		return nil
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.RGBAModel.Convert(m.At(x, y)).(color.RGBA)
+			for _, v := range [3]byte{c.R, c.G, c.B} {
+				if err := writeField(v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return w.WriteByte('\n')
+}
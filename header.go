@@ -0,0 +1,74 @@
+package ppm
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// headerScanner tokenizes the whitespace- and comment-delimited text that
+// makes up a PBM/PGM/PPM header and ASCII (P1/P2/P3) sample data. Per the
+// Netpbm spec, whitespace is any of " \t\n\r\v\f" and a "#" starts a
+// comment that runs to the next newline; comments and whitespace are never
+// part of the binary payload, so this scanner is only ever used before or
+// between samples, never while reading raw pixel bytes.
+type headerScanner struct {
+	br *bufio.Reader
+}
+
+func newHeaderScanner(br *bufio.Reader) *headerScanner {
+	return &headerScanner{br: br}
+}
+
+func isNetpbmSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// token reads the next whitespace-delimited token, skipping #-comments. It
+// returns io.ErrUnexpectedEOF if the input ends partway through a token,
+// and the underlying error (typically io.EOF) if it ends cleanly between
+// tokens.
+func (s *headerScanner) token() (string, error) {
+	var tok []byte
+	comment := false
+	for {
+		b, err := s.br.ReadByte()
+		if err != nil {
+			if len(tok) > 0 {
+				return "", io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		switch {
+		case b == '#':
+			comment = true
+		case comment:
+			if b == '\n' {
+				comment = false
+			}
+		case isNetpbmSpace(b):
+			if len(tok) > 0 {
+				return string(tok), nil
+			}
+		default:
+			tok = append(tok, b)
+		}
+	}
+}
+
+// int reads and parses the next token as a decimal integer.
+func (s *headerScanner) int() (int, error) {
+	tok, err := s.token()
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.Atoi(tok)
+	if err != nil {
+		return 0, errBadHeader
+	}
+	return v, nil
+}
@@ -0,0 +1,32 @@
+package ppm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// FuzzDecode exercises decodeHeader (and, for P7, decodePAMHeader) with
+// arbitrary input. Before headerScanner, malformed or truncated headers
+// could swallow ReadByte errors and loop forever instead of failing
+// cleanly; this guards against that regressing.
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte("P6\n2 2\n255\n" + strings.Repeat("\x00", 12)))
+	f.Add([]byte("P3\n1 1\n255\n255 255 255\n"))
+	f.Add([]byte("P2\n2 1\n255\n0 255\n"))
+	f.Add([]byte("P1\n2 1\n1 0\n"))
+	f.Add([]byte("P5\r\n2 2\r\n255\r\n" + strings.Repeat("\x00", 4)))
+	f.Add([]byte("P7\nWIDTH 1\nHEIGHT 1\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n\x00\x00\x00\x00"))
+	f.Add([]byte("P6\n-1 2\n255\n"))
+	f.Add([]byte("P6\n2 2\n99999\n"))
+	f.Add([]byte("P6"))
+	f.Add([]byte(""))
+	f.Add([]byte("# just a comment\nP6\n2 2\n255\n" + strings.Repeat("\x00", 12)))
+	f.Add([]byte("P6\n992\n9992929\n9\n")) // declared ~9.9B pixels; must be rejected before allocating
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Decode must never panic or hang on malformed input; any error
+		// return is acceptable.
+		_, _ = Decode(bytes.NewReader(data))
+	})
+}
@@ -0,0 +1,32 @@
+package ppm
+
+import "image"
+
+// decodePAM decodes a P7 (PAM) image. TUPLTYPE, not DEPTH alone, selects
+// the pixel layout, since DEPTH by itself is ambiguous (e.g. a depth-4
+// tuple could be RGB_ALPHA or CMYK): BLACKANDWHITE/GRAYSCALE (depth 1)
+// decodes to *image.Gray or *image.Gray16, RGB (depth 3) decodes to
+// *image.RGBA or *image.RGBA64, and RGB_ALPHA (depth 4) decodes to
+// *image.NRGBA or *image.NRGBA64. Any other TUPLTYPE, or one that doesn't
+// match its DEPTH, is rejected. All variants are decoded through readRow,
+// the same row-at-a-time path Reader.ReadRow uses.
+func (d *decoder) decodePAM() (image.Image, error) {
+	switch {
+	case d.depth == 1 && (d.tupleType == "BLACKANDWHITE" || d.tupleType == "GRAYSCALE"):
+		if d.maxVal > 255 {
+			return d.fillGray16(image.NewGray16(image.Rect(0, 0, d.width, d.height)))
+		}
+		return d.fillGray(image.NewGray(image.Rect(0, 0, d.width, d.height)))
+	case d.depth == 3 && d.tupleType == "RGB":
+		if d.maxVal > 255 {
+			return d.fillRGBA64(image.NewRGBA64(image.Rect(0, 0, d.width, d.height)))
+		}
+		return d.fillRGBA(image.NewRGBA(image.Rect(0, 0, d.width, d.height)))
+	case d.depth == 4 && d.tupleType == "RGB_ALPHA":
+		if d.maxVal > 255 {
+			return d.fillNRGBA64(image.NewNRGBA64(image.Rect(0, 0, d.width, d.height)))
+		}
+		return d.fillNRGBA(image.NewNRGBA(image.Rect(0, 0, d.width, d.height)))
+	}
+	return nil, errUnsupported
+}
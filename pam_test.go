@@ -0,0 +1,97 @@
+package ppm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDecodePAM(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		check func(t *testing.T, img image.Image)
+	}{
+		{
+			name: "GRAYSCALE depth 1",
+			data: []byte("P7\nWIDTH 2\nHEIGHT 1\nDEPTH 1\nMAXVAL 255\nTUPLTYPE GRAYSCALE\nENDHDR\n" +
+				string([]byte{0x10, 0xf0})),
+			check: func(t *testing.T, img image.Image) {
+				gray, ok := img.(*image.Gray)
+				if !ok {
+					t.Fatalf("Decode returned %T, want *image.Gray", img)
+				}
+				want := []color.Gray{{Y: 0x10}, {Y: 0xf0}}
+				for x, w := range want {
+					if got := gray.GrayAt(x, 0); got != w {
+						t.Errorf("pixel (%d,0) = %v, want %v", x, got, w)
+					}
+				}
+			},
+		},
+		{
+			name: "RGB depth 3",
+			data: []byte("P7\nWIDTH 2\nHEIGHT 1\nDEPTH 3\nMAXVAL 255\nTUPLTYPE RGB\nENDHDR\n" +
+				string([]byte{1, 2, 3, 4, 5, 6})),
+			check: func(t *testing.T, img image.Image) {
+				rgba, ok := img.(*image.RGBA)
+				if !ok {
+					t.Fatalf("Decode returned %T, want *image.RGBA", img)
+				}
+				want := []color.RGBA{{1, 2, 3, 0xff}, {4, 5, 6, 0xff}}
+				for x, w := range want {
+					if got := rgba.RGBAAt(x, 0); got != w {
+						t.Errorf("pixel (%d,0) = %v, want %v", x, got, w)
+					}
+				}
+			},
+		},
+		{
+			name: "RGB_ALPHA depth 4 converts to NRGBA with the real alpha channel",
+			data: []byte("P7\nWIDTH 1\nHEIGHT 1\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n" +
+				string([]byte{10, 20, 30, 128})),
+			check: func(t *testing.T, img image.Image) {
+				nrgba, ok := img.(*image.NRGBA)
+				if !ok {
+					t.Fatalf("Decode returned %T, want *image.NRGBA", img)
+				}
+				want := color.NRGBA{10, 20, 30, 128}
+				if got := nrgba.NRGBAAt(0, 0); got != want {
+					t.Errorf("pixel (0,0) = %v, want %v", got, want)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Decode(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			tt.check(t, img)
+		})
+	}
+}
+
+// TestDecodePAMRejectsTupleTypeMismatch guards the chunk0-3 TUPLTYPE fix:
+// DEPTH alone must never select the pixel layout, since a depth-4 tuple
+// could just as well be CMYK as RGB_ALPHA.
+func TestDecodePAMRejectsTupleTypeMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"depth 4 with unrecognized tupltype", []byte(
+			"P7\nWIDTH 1\nHEIGHT 1\nDEPTH 4\nMAXVAL 255\nTUPLTYPE CMYK\nENDHDR\n" + string([]byte{1, 2, 3, 4}))},
+		{"depth/tupltype mismatch", []byte(
+			"P7\nWIDTH 1\nHEIGHT 1\nDEPTH 3\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n" + string([]byte{1, 2, 3}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(tt.data)); err != errUnsupported {
+				t.Fatalf("Decode error = %v, want %v", err, errUnsupported)
+			}
+		})
+	}
+}
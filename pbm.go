@@ -0,0 +1,11 @@
+package ppm
+
+import "image"
+
+// decodePBM decodes a P1 (ASCII) or P4 (packed) PBM bitmap into an
+// *image.Gray. Per the PBM convention, a set bit is black (0x00) and a
+// clear bit is white (0xff). Both variants are decoded through readRow,
+// the same row-at-a-time path Reader.ReadRow uses.
+func (d *decoder) decodePBM() (image.Image, error) {
+	return d.fillGray(image.NewGray(image.Rect(0, 0, d.width, d.height)))
+}
@@ -0,0 +1,44 @@
+package ppm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDecodePBM(t *testing.T) {
+	// A 3x2 bitmap: row0 = 1 0 1, row1 = 0 1 0. Per the PBM convention a
+	// set bit decodes to black (0x00) and a clear bit to white (0xff).
+	want := [2][3]color.Gray{
+		{{Y: 0x00}, {Y: 0xff}, {Y: 0x00}},
+		{{Y: 0xff}, {Y: 0x00}, {Y: 0xff}},
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"P1 ascii", []byte("P1\n3 2\n1 0 1\n0 1 0\n")},
+		{"P4 packed", []byte("P4\n3 2\n" + string([]byte{0b10100000, 0b01000000}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Decode(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			gray, ok := img.(*image.Gray)
+			if !ok {
+				t.Fatalf("Decode returned %T, want *image.Gray", img)
+			}
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 3; x++ {
+					if got := gray.GrayAt(x, y); got != want[y][x] {
+						t.Errorf("pixel (%d,%d) = %v, want %v", x, y, got, want[y][x])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package ppm
+
+import "image"
+
+// decodePGM decodes a P2 (ASCII) or P5 (raw) PGM image into an *image.Gray
+// or *image.Gray16, depending on maxVal. Both variants are decoded through
+// readRow, the same row-at-a-time path Reader.ReadRow uses.
+func (d *decoder) decodePGM() (image.Image, error) {
+	if d.maxVal > 255 {
+		return d.fillGray16(image.NewGray16(image.Rect(0, 0, d.width, d.height)))
+	}
+	return d.fillGray(image.NewGray(image.Rect(0, 0, d.width, d.height)))
+}
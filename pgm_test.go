@@ -0,0 +1,56 @@
+package ppm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDecodePGM8(t *testing.T) {
+	// maxVal 100 rescales samples onto the full 0-255 range: 0->0, 50->127,
+	// 100->255.
+	want := []color.Gray{{Y: 0}, {Y: 127}, {Y: 255}}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"P2 ascii", []byte("P2\n3 1\n100\n0 50 100\n")},
+		{"P5 raw", []byte("P5\n3 1\n100\n" + string([]byte{0, 50, 100}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Decode(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			gray, ok := img.(*image.Gray)
+			if !ok {
+				t.Fatalf("Decode returned %T, want *image.Gray", img)
+			}
+			for x, w := range want {
+				if got := gray.GrayAt(x, 0); got != w {
+					t.Errorf("pixel (%d,0) = %v, want %v", x, got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodePGM16(t *testing.T) {
+	img, err := Decode(bytes.NewReader([]byte("P5\n2 1\n65535\n" + string([]byte{0x12, 0x34, 0x56, 0x78}))))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	gray16, ok := img.(*image.Gray16)
+	if !ok {
+		t.Fatalf("Decode returned %T, want *image.Gray16", img)
+	}
+	want := []color.Gray16{{Y: 0x1234}, {Y: 0x5678}}
+	for x, w := range want {
+		if got := gray16.Gray16At(x, 0); got != w {
+			t.Errorf("pixel (%d,0) = %v, want %v", x, got, w)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package ppm
+
+import "image"
+
+// decodePPM decodes a P3 (ASCII) or P6 (raw) PPM image into an *image.RGBA
+// or *image.RGBA64, depending on maxVal. Both variants are decoded through
+// readRow, the same row-at-a-time path Reader.ReadRow uses.
+func (d *decoder) decodePPM() (image.Image, error) {
+	if d.maxVal > 255 {
+		return d.fillRGBA64(image.NewRGBA64(image.Rect(0, 0, d.width, d.height)))
+	}
+	return d.fillRGBA(image.NewRGBA(image.Rect(0, 0, d.width, d.height)))
+}
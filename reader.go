@@ -1,30 +1,52 @@
-// Package ppm implements a Portable Pixel Map (PPM) image decoder and encoder.
+// Package ppm implements decoders for the Netpbm image formats: PBM
+// (P1/P4), PGM (P2/P5), PPM (P3/P6) and PAM (P7); and an encoder for PPM,
+// the only one of those formats this package can also write.
 //
-// The PPM specification is at http://netpbm.sourceforge.net/doc/ppm.html.
+// The specifications are at http://netpbm.sourceforge.net/doc/.
 package ppm
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"image"
 	"image/color"
 	"io"
 	"strconv"
+	"strings"
 )
 
 var (
 	errBadHeader   = errors.New("ppm: invalid header")
 	errNotEnough   = errors.New("ppm: not enough image data")
-	errUnsupported = errors.New("ppm: unsupported format (maxVal != 255)")
+	errUnsupported = errors.New("ppm: unsupported PAM tuple depth")
+	errRowSize     = errors.New("ppm: ReadRow: dst has the wrong length")
 )
 
+// maxSamples bounds width*height (or, for PAM, width*height*depth) so a
+// declared size can't force an allocation (image.NewRGBA, etc.) large
+// enough to OOM-kill the process before a single byte of pixel data has
+// been read. 1<<28 samples is generous for any real image (a 16384x16384
+// RGBA frame is ~27% of it) while still bounding worst-case allocation at
+// a few GiB.
+const maxSamples = 1 << 28
+
 func init() {
-	image.RegisterFormat("ppm", "P6", Decode, DecodeConfig)
+	image.RegisterFormat("pbm", "P1", Decode, DecodeConfig)
+	image.RegisterFormat("pgm", "P2", Decode, DecodeConfig)
 	image.RegisterFormat("ppm", "P3", Decode, DecodeConfig)
+	image.RegisterFormat("pbm", "P4", Decode, DecodeConfig)
+	image.RegisterFormat("pgm", "P5", Decode, DecodeConfig)
+	image.RegisterFormat("ppm", "P6", Decode, DecodeConfig)
+	image.RegisterFormat("pam", "P7", Decode, DecodeConfig)
 }
 
-// Decode reads a PPM image from Reader r and returns it as an image.Image.
+// Decode reads a Netpbm image (PBM, PGM, PPM or PAM) from Reader r and
+// returns it as an image.Image.
+//
+// The concrete type returned depends on the format and sample depth: PBM
+// decodes to *image.Gray, PGM to *image.Gray or *image.Gray16, PPM to
+// *image.RGBA or *image.RGBA64, and PAM to one of those four or
+// *image.NRGBA / *image.NRGBA64 for RGB_ALPHA tuples.
 func Decode(r io.Reader) (image.Image, error) {
 	var d decoder
 	img, err := d.decode(r, false)
@@ -34,144 +56,257 @@ func Decode(r io.Reader) (image.Image, error) {
 	return img, nil
 }
 
-// DecodeConfig returns the color model and dimensions of a PPM image without
-// decoding the entire image.
+// DecodeConfig returns the color model and dimensions of a Netpbm image
+// without decoding the entire image.
 func DecodeConfig(r io.Reader) (image.Config, error) {
 	var d decoder
 	if _, err := d.decode(r, true); err != nil {
 		return image.Config{}, err
 	}
 	return image.Config{
-		ColorModel: color.RGBAModel,
+		ColorModel: d.colorModel(),
 		Width:      d.width,
 		Height:     d.height,
 	}, nil
 }
 
-// decoder is the type used to decode a PPM file.
+// decoder is the type used to decode a Netpbm file.
 type decoder struct {
 	br *bufio.Reader
+	hs *headerScanner
 
 	// from header
 	magicNumber string
 	width       int
 	height      int
-	maxVal      int // 255, TODO: support 0 < maxVal < 65536
+	maxVal      int // in [1, 65535]
+
+	// PAM (P7) only
+	depth     int
+	tupleType string
+
+	// rowScratch is reused across ReadRow calls for binary (non-ASCII)
+	// rows so streaming a large image doesn't allocate per row.
+	rowScratch []byte
 }
 
 func (d *decoder) decode(r io.Reader, configOnly bool) (image.Image, error) {
 	d.br = bufio.NewReader(r)
-	var err error
+	d.hs = newHeaderScanner(d.br)
 
-	// decode header
-	err = d.decodeHeader()
-	if err != nil {
+	if err := d.decodeHeader(); err != nil {
 		return nil, err
 	}
 	if configOnly {
 		return nil, nil
 	}
 
-	// decode image
-	pixel := make([]byte, 3)
-
-	img := image.NewRGBA(image.Rect(0, 0, d.width, d.height))
+	switch d.magicNumber {
+	case "P1", "P4":
+		return d.decodePBM()
+	case "P2", "P5":
+		return d.decodePGM()
+	case "P3", "P6":
+		return d.decodePPM()
+	case "P7":
+		return d.decodePAM()
+	}
+	return nil, errBadHeader
+}
 
-	if d.magicNumber == "P6" {
-		for y := 0; y < d.height; y++ {
-			for x := 0; x < d.width; x++ {
-				_, err = io.ReadFull(d.br, pixel)
-				if err != nil {
-					return nil, errNotEnough
-				}
-				img.SetRGBA(x, y, color.RGBA{pixel[0], pixel[1], pixel[2], 0xff})
-			}
+// colorModel returns the color.Model that decoding this file's header
+// implies, without requiring the pixel data to have been read.
+func (d *decoder) colorModel() color.Model {
+	switch d.magicNumber {
+	case "P1", "P4":
+		return color.GrayModel
+	case "P2", "P5":
+		if d.maxVal > 255 {
+			return color.Gray16Model
 		}
-	} else if d.magicNumber == "P3" {
-		for y := 0; y < d.height; y++ {
-			for x := 0; x < d.width; x++ {
-				for s := 0; s < 3; s++ {
-					pixel[s], err = d.getSubPixel()
-					if err != nil {
-						return nil, errNotEnough
-					}
-				}
-				img.SetRGBA(x, y, color.RGBA{pixel[0], pixel[1], pixel[2], 0xff})
-
+		return color.GrayModel
+	case "P3", "P6":
+		if d.maxVal > 255 {
+			return color.RGBA64Model
+		}
+		return color.RGBAModel
+	case "P7":
+		switch {
+		case d.depth == 1 && (d.tupleType == "BLACKANDWHITE" || d.tupleType == "GRAYSCALE"):
+			if d.maxVal > 255 {
+				return color.Gray16Model
+			}
+			return color.GrayModel
+		case d.depth == 4 && d.tupleType == "RGB_ALPHA":
+			if d.maxVal > 255 {
+				return color.NRGBA64Model
 			}
+			return color.NRGBAModel
+		case d.depth == 3 && d.tupleType == "RGB":
+			if d.maxVal > 255 {
+				return color.RGBA64Model
+			}
+			return color.RGBAModel
+		default:
+			return color.RGBAModel
 		}
 	}
-	return img, nil
+	return color.RGBAModel
 }
 
-func (d *decoder) decodeHeader() error {
-	var err error
-	var b byte
-	header := make([]byte, 0)
-
-	comment := false
-	for fields := 0; fields < 4; {
-		b, _ = d.br.ReadByte()
-		if b == '#' {
-			comment = true
-		} else if !comment {
-			header = append(header, b)
-		}
-		if comment && b == '\n' {
-			comment = false
-		} else if !comment && (b == ' ' || b == '\n' || b == '\t') {
-			fields++
-		}
+// scale8 rescales a sample read from a file with the decoder's maxVal onto
+// the full 0-255 range. It is the single shared rescale path for every
+// 8-bit-sample format (PGM, PPM, PAM).
+func (d *decoder) scale8(v byte) byte {
+	if d.maxVal == 255 {
+		return v
 	}
-	headerFields := bytes.Fields(header)
+	return byte(int(v) * 255 / d.maxVal)
+}
 
-	d.magicNumber = string(headerFields[0])
-	if d.magicNumber != "P6" {
-		if d.magicNumber != "P3" {
-			return errBadHeader
-		}
+// scale16 rescales a sample read from a file with the decoder's maxVal
+// onto the full 0-65535 range. It is the single shared rescale path for
+// every 16-bit-sample format (PGM, PPM, PAM).
+func (d *decoder) scale16(v uint16) uint16 {
+	if d.maxVal == 65535 {
+		return v
 	}
-	d.width, err = strconv.Atoi(string(headerFields[1]))
+	// uint32, not int: v*65535 can exceed the range of a 32-bit int (the
+	// width of Go's int on 386/arm), which would silently overflow.
+	return uint16(uint32(v) * 65535 / uint32(d.maxVal))
+}
+
+// decodeHeader parses the magic number and the fields that follow it. PBM,
+// PGM and PPM share the same "magic width height [maxval]" layout; PAM (P7)
+// uses a distinct keyword-based header and is parsed separately.
+func (d *decoder) decodeHeader() error {
+	magic, err := d.hs.token()
 	if err != nil {
-		return errBadHeader
+		return err
 	}
-	d.height, err = strconv.Atoi(string(headerFields[2]))
-	if err != nil {
+	d.magicNumber = magic
+
+	if magic == "P7" {
+		return d.decodePAMHeader()
+	}
+
+	switch magic {
+	case "P1", "P2", "P3", "P4", "P5", "P6":
+	default:
 		return errBadHeader
 	}
 
-	d.maxVal, err = strconv.Atoi(string(headerFields[3]))
+	d.width, err = d.hs.int()
+	if err != nil {
+		return err
+	}
+	d.height, err = d.hs.int()
 	if err != nil {
+		return err
+	}
+	if d.width <= 0 || d.height <= 0 {
 		return errBadHeader
-	} else if d.maxVal != 255 {
-		return errUnsupported
+	}
+	if err := d.checkDimensions(d.channels()); err != nil {
+		return err
+	}
+
+	switch magic {
+	case "P1", "P4":
+		// PBM has no maxval field; samples are single bits.
+		d.maxVal = 1
+	default:
+		d.maxVal, err = d.hs.int()
+		if err != nil {
+			return err
+		}
+		if d.maxVal < 1 || d.maxVal > 65535 {
+			return errBadHeader
+		}
 	}
 	return nil
 }
 
-func (d *decoder) getSubPixel() (byte, error) {
-	var err error
-	var b byte
-	var val int
-	subpix := make([]byte, 0)
+// checkDimensions rejects a declared width*height*channels that exceeds
+// maxSamples, using int64 arithmetic so the multiplication itself can't
+// overflow and mask an oversized value.
+func (d *decoder) checkDimensions(channels int) error {
+	if int64(d.width)*int64(d.height)*int64(channels) > maxSamples {
+		return errBadHeader
+	}
+	return nil
+}
 
-	comment := false
+// decodePAMHeader parses a PAM "KEYWORD value" header, terminated by a line
+// containing only ENDHDR.
+func (d *decoder) decodePAMHeader() error {
 	for {
-		b, _ = d.br.ReadByte()
-		if b == '#' {
-			comment = true
-		} else if !comment && (b == ' ' || b == '\n' || b == '\t') {
+		line, rerr := d.br.ReadString('\n')
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			if rerr != nil {
+				return io.ErrUnexpectedEOF
+			}
+			continue
+		}
+		if line == "ENDHDR" {
 			break
-		} else if !comment {
-			subpix = append(subpix, b)
 		}
-		if comment && b == '\n' {
-			comment = false
+		if strings.HasPrefix(line, "#") {
+			if rerr != nil {
+				return io.ErrUnexpectedEOF
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return errBadHeader
+		}
+		var err error
+		switch fields[0] {
+		case "WIDTH":
+			d.width, err = strconv.Atoi(fields[1])
+		case "HEIGHT":
+			d.height, err = strconv.Atoi(fields[1])
+		case "DEPTH":
+			d.depth, err = strconv.Atoi(fields[1])
+		case "MAXVAL":
+			d.maxVal, err = strconv.Atoi(fields[1])
+		case "TUPLTYPE":
+			d.tupleType = fields[1]
+		}
+		if err != nil {
+			return errBadHeader
+		}
+		if rerr != nil {
+			return io.ErrUnexpectedEOF
 		}
 	}
-	val, err = strconv.Atoi(string(subpix))
+	if d.width <= 0 || d.height <= 0 || d.depth <= 0 {
+		return errBadHeader
+	}
+	if d.maxVal < 1 || d.maxVal > 65535 {
+		return errBadHeader
+	}
+	if err := d.checkDimensions(d.depth); err != nil {
+		return err
+	}
+	return nil
+}
+
+// getSubPixel reads one whitespace-delimited decimal sample from an ASCII
+// (P1/P2/P3) image. Values may exceed 255 when maxVal does.
+func (d *decoder) getSubPixel() (int, error) {
+	val, err := d.hs.int()
 	if err != nil {
 		return 0, errNotEnough
 	}
-	return byte(val), nil
+	// A sample outside [0, maxVal] is malformed; without this check it
+	// would silently wrap around when truncated to a byte or uint16.
+	if val < 0 || val > d.maxVal {
+		return 0, errNotEnough
+	}
+	return val, nil
 }
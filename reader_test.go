@@ -0,0 +1,29 @@
+package ppm
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestDecodeRejectsOversizedDimensions guards against a declared width and
+// height large enough to make image.NewRGBA (or similar) request an
+// unreasonable allocation before a single byte of pixel data has been
+// read. Without checkDimensions, this exact header (width=992,
+// height=9992929) drives an ~40GB allocation request and can OOM-kill the
+// process rather than return an error.
+func TestDecodeRejectsOversizedDimensions(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"PPM", []byte("P6\n992\n9992929\n9\n")},
+		{"PAM", []byte("P7\nWIDTH 100000\nHEIGHT 100000\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(tt.data)); err == nil {
+				t.Fatal("Decode succeeded on an oversized declared image; want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package ppm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(16 * x),
+				G: uint8(16 * y),
+				B: uint8(16 * (x + y)),
+				A: 0xff,
+			})
+		}
+	}
+	return img
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts EncodeOptions
+	}{
+		{"raw", EncodeOptions{Encoding: Raw}},
+		{"ascii", EncodeOptions{Encoding: ASCII}},
+	}
+
+	want := testImage()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.opts.Encode(&buf, want); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			got, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			gotRGBA, ok := got.(*image.RGBA)
+			if !ok {
+				t.Fatalf("Decode returned %T, want *image.RGBA", got)
+			}
+			if gotRGBA.Bounds() != want.Bounds() {
+				t.Fatalf("Bounds = %v, want %v", gotRGBA.Bounds(), want.Bounds())
+			}
+			b := want.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					if gotRGBA.RGBAAt(x, y) != want.RGBAAt(x, y) {
+						t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, gotRGBA.RGBAAt(x, y), want.RGBAAt(x, y))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeDefaultsToRaw(t *testing.T) {
+	want := testImage()
+	var buf bytes.Buffer
+	if err := Encode(&buf, want); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if magic := buf.Bytes()[:2]; string(magic) != "P6" {
+		t.Fatalf("magic number = %q, want P6", magic)
+	}
+}
@@ -0,0 +1,292 @@
+package ppm
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Reader provides row-by-row access to a Netpbm image, for callers that
+// want to process very large files (satellite imagery, scientific
+// datasets, ...) without holding the whole decoded image in memory at
+// once. This mirrors the progressive access image/png exposes through its
+// low-level chunk reader.
+type Reader struct {
+	d *decoder
+	y int
+}
+
+// NewReader parses the header of the Netpbm image in r and returns a
+// Reader positioned at the first row. The header is read eagerly; pixel
+// data is read lazily, one row at a time, via ReadRow.
+func NewReader(r io.Reader) (*Reader, error) {
+	d := &decoder{}
+	d.br = bufio.NewReader(r)
+	d.hs = newHeaderScanner(d.br)
+	if err := d.decodeHeader(); err != nil {
+		return nil, err
+	}
+	return &Reader{d: d}, nil
+}
+
+// Bounds returns the image's dimensions, always starting at (0, 0).
+func (rd *Reader) Bounds() image.Rectangle {
+	return image.Rect(0, 0, rd.d.width, rd.d.height)
+}
+
+// ColorModel returns the color model implied by the image's header.
+func (rd *Reader) ColorModel() color.Model {
+	return rd.d.colorModel()
+}
+
+// MaxVal returns the image's maxval (or, for PBM, 1).
+func (rd *Reader) MaxVal() int {
+	return rd.d.maxVal
+}
+
+// RowSize returns the number of bytes ReadRow expects its argument to be:
+// one sample per channel when MaxVal() <= 255, or two big-endian bytes per
+// channel otherwise.
+func (rd *Reader) RowSize() int {
+	bpc := 1
+	if rd.d.maxVal > 255 {
+		bpc = 2
+	}
+	return rd.d.width * rd.d.channels() * bpc
+}
+
+// ReadRow reads the next scanline into dst, which must have length
+// RowSize(). Samples are already rescaled to the full 0-255 or 0-65535
+// range and are in the source format's channel order (one channel for
+// PBM/PGM, R/G/B for PPM, DEPTH channels for PAM) — there is no synthesized
+// alpha channel. ReadRow returns io.EOF once all rows have been read.
+func (rd *Reader) ReadRow(dst []byte) error {
+	if rd.y >= rd.d.height {
+		return io.EOF
+	}
+	if len(dst) != rd.RowSize() {
+		return errRowSize
+	}
+	if err := rd.d.readRow(dst); err != nil {
+		return err
+	}
+	rd.y++
+	return nil
+}
+
+// channels returns the number of samples per pixel for the decoder's
+// format.
+func (d *decoder) channels() int {
+	switch d.magicNumber {
+	case "P1", "P2", "P4", "P5":
+		return 1
+	case "P3", "P6":
+		return 3
+	case "P7":
+		return d.depth
+	}
+	return 0
+}
+
+// readRow fills dst (length RowSize()) with one decoded, rescaled scanline.
+// This is the package's single pixel-decoding path: Decode drives it
+// through the fill* helpers below and the exported Reader drives it
+// through ReadRow, so the two can never drift out of sync with each other.
+func (d *decoder) readRow(dst []byte) error {
+	switch d.magicNumber {
+	case "P1", "P4":
+		return d.readRowBits(dst)
+	default:
+		return d.readRowSamples(dst, d.channels())
+	}
+}
+
+func (d *decoder) readRowBits(dst []byte) error {
+	if d.magicNumber == "P1" {
+		for x := 0; x < d.width; x++ {
+			v, err := d.getSubPixel()
+			if err != nil {
+				return err
+			}
+			dst[x] = bitByte(v != 0)
+		}
+		return nil
+	}
+
+	rowBytes := (d.width + 7) / 8
+	row := d.scratchBuf(rowBytes)
+	if _, err := io.ReadFull(d.br, row); err != nil {
+		return errNotEnough
+	}
+	for x := 0; x < d.width; x++ {
+		bit := row[x/8]&(0x80>>uint(x%8)) != 0
+		dst[x] = bitByte(bit)
+	}
+	return nil
+}
+
+// scratchBuf returns d.rowScratch resized to length n, reusing its
+// backing array across calls instead of allocating a fresh one each row.
+func (d *decoder) scratchBuf(n int) []byte {
+	if cap(d.rowScratch) < n {
+		d.rowScratch = make([]byte, n)
+	}
+	return d.rowScratch[:n]
+}
+
+func bitByte(set bool) byte {
+	if set {
+		return 0x00
+	}
+	return 0xff
+}
+
+func (d *decoder) readRowSamples(dst []byte, channels int) error {
+	ascii := d.magicNumber == "P1" || d.magicNumber == "P2" || d.magicNumber == "P3"
+	n := d.width * channels
+
+	if ascii {
+		if d.maxVal > 255 {
+			for i := 0; i < n; i++ {
+				v, err := d.getSubPixel()
+				if err != nil {
+					return err
+				}
+				sv := d.scale16(uint16(v))
+				dst[2*i], dst[2*i+1] = byte(sv>>8), byte(sv)
+			}
+			return nil
+		}
+		for i := 0; i < n; i++ {
+			v, err := d.getSubPixel()
+			if err != nil {
+				return err
+			}
+			dst[i] = d.scale8(byte(v))
+		}
+		return nil
+	}
+
+	// Binary formats: read the whole row into a reused scratch buffer and
+	// expand it, which is far faster (and allocation-free) compared to one
+	// ReadByte/ReadFull per sample.
+	if d.maxVal > 255 {
+		raw := d.scratchBuf(n * 2)
+		if _, err := io.ReadFull(d.br, raw); err != nil {
+			return errNotEnough
+		}
+		for i := 0; i < n; i++ {
+			sv := uint16(raw[2*i])<<8 | uint16(raw[2*i+1])
+			// A raw sample greater than maxVal is malformed; without this
+			// check it would silently wrap when rescaled, the same way the
+			// ASCII path is already guarded in getSubPixel.
+			if int(sv) > d.maxVal {
+				return errNotEnough
+			}
+			v := d.scale16(sv)
+			dst[2*i], dst[2*i+1] = byte(v>>8), byte(v)
+		}
+		return nil
+	}
+	raw := d.scratchBuf(n)
+	if _, err := io.ReadFull(d.br, raw); err != nil {
+		return errNotEnough
+	}
+	for i := 0; i < n; i++ {
+		if int(raw[i]) > d.maxVal {
+			return errNotEnough
+		}
+		dst[i] = d.scale8(raw[i])
+	}
+	return nil
+}
+
+// fillGray fills a single-channel, maxVal<=255 image row by row via
+// readRow. The row layout readRow produces for a 1-channel image already
+// matches Gray's Pix layout exactly, so each row is a straight copy.
+func (d *decoder) fillGray(img *image.Gray) (image.Image, error) {
+	row := make([]byte, d.width)
+	for y := 0; y < d.height; y++ {
+		if err := d.readRow(row); err != nil {
+			return nil, err
+		}
+		copy(img.Pix[y*img.Stride:y*img.Stride+d.width], row)
+	}
+	return img, nil
+}
+
+// fillGray16 is fillGray for maxVal > 255 images, copying the big-endian
+// 2-byte samples readRow produces straight into Gray16's Pix.
+func (d *decoder) fillGray16(img *image.Gray16) (image.Image, error) {
+	row := make([]byte, d.width*2)
+	for y := 0; y < d.height; y++ {
+		if err := d.readRow(row); err != nil {
+			return nil, err
+		}
+		copy(img.Pix[y*img.Stride:y*img.Stride+d.width*2], row)
+	}
+	return img, nil
+}
+
+// fillRGBA fills a 3-channel (no source alpha), maxVal<=255 image,
+// expanding each RGB triple readRow produces into RGBA's 4-byte-per-pixel
+// Pix layout with a synthesized opaque alpha.
+func (d *decoder) fillRGBA(img *image.RGBA) (image.Image, error) {
+	row := make([]byte, d.width*3)
+	for y := 0; y < d.height; y++ {
+		if err := d.readRow(row); err != nil {
+			return nil, err
+		}
+		dst := img.Pix[y*img.Stride : y*img.Stride+d.width*4]
+		for x := 0; x < d.width; x++ {
+			s, p := row[x*3:x*3+3], dst[x*4:x*4+4]
+			p[0], p[1], p[2] = s[0], s[1], s[2]
+			p[3] = 0xff
+		}
+	}
+	return img, nil
+}
+
+// fillRGBA64 is fillRGBA for maxVal > 255 images.
+func (d *decoder) fillRGBA64(img *image.RGBA64) (image.Image, error) {
+	row := make([]byte, d.width*6)
+	for y := 0; y < d.height; y++ {
+		if err := d.readRow(row); err != nil {
+			return nil, err
+		}
+		dst := img.Pix[y*img.Stride : y*img.Stride+d.width*8]
+		for x := 0; x < d.width; x++ {
+			s, p := row[x*6:x*6+6], dst[x*8:x*8+8]
+			copy(p[:6], s)
+			p[6], p[7] = 0xff, 0xff
+		}
+	}
+	return img, nil
+}
+
+// fillNRGBA fills a 4-channel RGB_ALPHA, maxVal<=255 image. The row readRow
+// produces already carries the real alpha channel in NRGBA's byte order,
+// so each row is a straight copy.
+func (d *decoder) fillNRGBA(img *image.NRGBA) (image.Image, error) {
+	row := make([]byte, d.width*4)
+	for y := 0; y < d.height; y++ {
+		if err := d.readRow(row); err != nil {
+			return nil, err
+		}
+		copy(img.Pix[y*img.Stride:y*img.Stride+d.width*4], row)
+	}
+	return img, nil
+}
+
+// fillNRGBA64 is fillNRGBA for maxVal > 255 images.
+func (d *decoder) fillNRGBA64(img *image.NRGBA64) (image.Image, error) {
+	row := make([]byte, d.width*8)
+	for y := 0; y < d.height; y++ {
+		if err := d.readRow(row); err != nil {
+			return nil, err
+		}
+		copy(img.Pix[y*img.Stride:y*img.Stride+d.width*8], row)
+	}
+	return img, nil
+}